@@ -0,0 +1,62 @@
+// Command promql-migrate-testdata drives promqltest.MigrateTestData (and
+// its preview/emit variants) from the command line, so that migrating the
+// PromQL testdata tree to the new annotation syntax can be gated in CI
+// instead of run blind:
+//
+//	promql-migrate-testdata -preview            # print a diff report, touch nothing
+//	promql-migrate-testdata -mode=tolerant       # rewrite .test files in place
+//	promql-migrate-testdata -emit=yaml -preview  # preview a YAML migration
+//
+// In -preview mode, the command exits with status 1 if any file would
+// change, so a CI job can run it on every PR and fail the build until the
+// migration is applied for real.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/prometheus/prometheus/promql/promqltest"
+)
+
+var (
+	mode    = flag.String("mode", "strict", "migration mode: strict, basic, or tolerant")
+	emit    = flag.String("emit", "text", "output format: text, yaml, or both")
+	preview = flag.Bool("preview", false, "print a diff report instead of rewriting files; exits 1 if anything would change")
+)
+
+func main() {
+	flag.Parse()
+
+	if *preview {
+		os.Exit(runPreview())
+	}
+	if err := promqltest.MigrateTestDataEmit(*mode, *emit); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func runPreview() int {
+	previews, err := promqltest.PreviewMigrateTestDataEmit(*mode, *emit)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	for _, p := range previews {
+		fmt.Printf("%s: %d touched, %d skipped, %d failed\n", p.Path, p.BlocksTouched, p.BlocksSkipped, p.BlocksFailed)
+		if p.Diff != "" {
+			fmt.Print(p.Diff)
+		}
+		if p.YAMLDiff != "" {
+			fmt.Print(p.YAMLDiff)
+		}
+	}
+
+	if len(previews) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,71 @@
+// Command promqltestfmt formats PromQL .test files, the way gofmt formats Go
+// source: it reads each file, canonicalizes it through promqltestfmt.Format,
+// and either rewrites the file in place (-w) or lists files that would
+// change (-l). With neither flag, the formatted output is written to stdout.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/prometheus/prometheus/promql/promqltest/promqltestfmt"
+)
+
+var (
+	write = flag.Bool("w", false, "write result to (source) file instead of stdout")
+	list  = flag.Bool("l", false, "list files whose formatting differs")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: promqltestfmt [-w] [-l] path.test [path.test ...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	exitCode := 0
+	for _, path := range flag.Args() {
+		if err := formatFile(path); err != nil {
+			fmt.Fprintf(os.Stderr, "promqltestfmt: %s: %v\n", path, err)
+			exitCode = 1
+		}
+	}
+	os.Exit(exitCode)
+}
+
+func formatFile(path string) error {
+	orig, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	f, err := promqltestfmt.Parse(orig)
+	if err != nil {
+		return err
+	}
+	formatted := promqltestfmt.Format(f)
+
+	if !*list && !*write {
+		_, err := os.Stdout.Write(formatted)
+		return err
+	}
+
+	if bytes.Equal(orig, formatted) {
+		return nil
+	}
+
+	if *list {
+		fmt.Println(path)
+	}
+	if *write {
+		return os.WriteFile(path, formatted, 0o644)
+	}
+	return nil
+}
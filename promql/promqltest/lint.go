@@ -0,0 +1,174 @@
+package promqltest
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/prometheus/prometheus/promql/promqltest/promqltestfmt"
+)
+
+// Diagnostic is a single finding reported by LintTestData, formatted the way
+// compilers and vet report problems: file:line:col followed by a message.
+type Diagnostic struct {
+	File    string
+	Line    int
+	Col     int
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", d.File, d.Line, d.Col, d.Message)
+}
+
+// LintOptions configures LintTestData.
+type LintOptions struct {
+	// Dir is the testdata directory to lint, recursively. Defaults to
+	// "promql/promqltest/testdata" when empty.
+	Dir string
+}
+
+var deprecatedEvalCommands = map[string]bool{
+	"eval_fail":    true,
+	"eval_warn":    true,
+	"eval_info":    true,
+	"eval_ordered": true,
+}
+
+// LintTestData parses every .test and .test.yaml file under opts.Dir without
+// rewriting anything, and reports:
+//
+//   - eval blocks with no `expect` annotation describing their outcome
+//   - deprecated eval_fail/eval_warn/eval_info/eval_ordered commands, or
+//     deprecated expected_fail_message/expected_fail_regexp lines
+//   - inconsistent indentation (mixed tabs and spaces) within a block
+//   - `expect fail` annotations that set both msg: and regex:
+//
+// It is the natural companion to MigrateTestData: once a repository has
+// migrated, LintTestData lets CI catch regressions back to the old syntax.
+//
+// Diagnostics are derived entirely from the *promqltestfmt.File that
+// LoadTestFile produces (parsing .test and .test.yaml files alike into the
+// same AST), rather than from a second hand-rolled scanner, so that lint
+// rules never drift out of sync with the grammar Parse and Format already
+// agree on. The tradeoff is column precision: since the AST doesn't track
+// the position of individual annotation lines, every diagnostic for a block
+// is reported against that block's header line, with Col always 1. Blocks
+// converted from a .test.yaml file carry no source position, so their
+// diagnostics have Line 0.
+//
+// A file that fails to parse is reported as a Diagnostic on that file
+// rather than aborting the scan: one malformed file shouldn't hide every
+// finding LintTestData already collected, or skip every file after it.
+func LintTestData(opts LintOptions) ([]Diagnostic, error) {
+	dir := opts.Dir
+	if dir == "" {
+		dir = "promql/promqltest/testdata"
+	}
+
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !(strings.HasSuffix(path, ".test") || strings.HasSuffix(path, ".test.yaml")) {
+			return nil
+		}
+		paths = append(paths, path)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk testdata directory: %w", err)
+	}
+
+	var diags []Diagnostic
+	for _, path := range paths {
+		f, err := LoadTestFile(path)
+		if err != nil {
+			diags = append(diags, Diagnostic{
+				File: path, Line: 1, Col: 1,
+				Message: fmt.Sprintf("failed to parse: %v", err),
+			})
+			continue
+		}
+		diags = append(diags, lintFile(path, f)...)
+	}
+	return diags, nil
+}
+
+func lintFile(path string, f *promqltestfmt.File) []Diagnostic {
+	var diags []Diagnostic
+
+	for _, blk := range f.Blocks {
+		switch v := blk.(type) {
+		case *promqltestfmt.LoadBlock:
+			if v.MixedIndent {
+				diags = append(diags, Diagnostic{
+					File: path, Line: v.Line, Col: 1,
+					Message: "inconsistent indentation (mixed tabs and spaces) within load block",
+				})
+			}
+
+		case *promqltestfmt.EvalBlock:
+			diags = append(diags, lintEvalBlock(path, v)...)
+		}
+	}
+	return diags
+}
+
+func lintEvalBlock(path string, eb *promqltestfmt.EvalBlock) []Diagnostic {
+	var diags []Diagnostic
+
+	if deprecatedEvalCommands[eb.Command] {
+		diags = append(diags, Diagnostic{
+			File: path, Line: eb.Line, Col: 1,
+			Message: fmt.Sprintf("deprecated command %q, use `eval` with explicit `expect` annotations instead", eb.Command),
+		})
+	}
+	if eb.ExpectedFailMessage != "" {
+		diags = append(diags, Diagnostic{
+			File: path, Line: eb.Line, Col: 1,
+			Message: "deprecated expected_fail_message, use `expect fail msg:` instead",
+		})
+	}
+	if eb.ExpectedFailRegexp != "" {
+		diags = append(diags, Diagnostic{
+			File: path, Line: eb.Line, Col: 1,
+			Message: "deprecated expected_fail_regexp, use `expect fail regex:` instead",
+		})
+	}
+	if eb.Command == "eval" && len(eb.Expectations) == 0 {
+		diags = append(diags, Diagnostic{
+			File: path, Line: eb.Line, Col: 1,
+			Message: "eval block has no expect annotation",
+		})
+	}
+	if eb.MixedIndent {
+		diags = append(diags, Diagnostic{
+			File: path, Line: eb.Line, Col: 1,
+			Message: "inconsistent indentation (mixed tabs and spaces) within eval block",
+		})
+	}
+
+	var hasFailMsg, hasFailRegex bool
+	for _, exp := range eb.Expectations {
+		if exp.Kind != "fail" {
+			continue
+		}
+		if exp.Msg != "" {
+			hasFailMsg = true
+		}
+		if exp.Regex != "" {
+			hasFailRegex = true
+		}
+	}
+	if hasFailMsg && hasFailRegex {
+		diags = append(diags, Diagnostic{
+			File: path, Line: eb.Line, Col: 1,
+			Message: "expect fail: msg: and regex: are mutually exclusive",
+		})
+	}
+
+	return diags
+}
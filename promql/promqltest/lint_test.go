@@ -0,0 +1,153 @@
+package promqltest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func lintContents(t *testing.T, content string) []Diagnostic {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.test"), []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	diags, err := LintTestData(LintOptions{Dir: dir})
+	if err != nil {
+		t.Fatalf("LintTestData: %v", err)
+	}
+	return diags
+}
+
+func messages(diags []Diagnostic) []string {
+	out := make([]string, len(diags))
+	for i, d := range diags {
+		out[i] = d.Message
+	}
+	return out
+}
+
+func TestLintTestDataMissingExpectAnnotation(t *testing.T) {
+	diags := lintContents(t, "eval instant at 1m metric\n  metric 1\n")
+	if len(diags) != 1 || diags[0].Message != "eval block has no expect annotation" {
+		t.Fatalf("diagnostics = %v, want a single missing-expect diagnostic", messages(diags))
+	}
+	if diags[0].Line != 1 {
+		t.Fatalf("Line = %d, want 1", diags[0].Line)
+	}
+}
+
+func TestLintTestDataDeprecatedCommand(t *testing.T) {
+	diags := lintContents(t, "eval_fail instant at 1m metric\n  expect fail msg:boom\n")
+	if len(diags) != 1 || diags[0].Message != `deprecated command "eval_fail", use `+"`eval`"+` with explicit `+"`expect`"+` annotations instead` {
+		t.Fatalf("diagnostics = %v, want a single deprecated-command diagnostic", messages(diags))
+	}
+}
+
+func TestLintTestDataDeprecatedExpectedFailFields(t *testing.T) {
+	diags := lintContents(t, "eval instant at 1m metric\n  expected_fail_message boom\n  expect fail msg:boom\n")
+	got := messages(diags)
+	want := []string{"deprecated expected_fail_message, use `expect fail msg:` instead"}
+	if len(got) != len(want) || got[0] != want[0] {
+		t.Fatalf("diagnostics = %v, want %v", got, want)
+	}
+}
+
+func TestLintTestDataMutuallyExclusiveFailQualifiers(t *testing.T) {
+	diags := lintContents(t, "eval instant at 1m metric\n  expect fail msg:boom\n  expect fail regex:boom.*\n")
+	found := false
+	for _, d := range diags {
+		if d.Message == "expect fail: msg: and regex: are mutually exclusive" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("diagnostics = %v, want the mutually-exclusive diagnostic", messages(diags))
+	}
+}
+
+func TestLintTestDataMixedIndent(t *testing.T) {
+	diags := lintContents(t, "eval instant at 1m metric\n  expect no_warn\n\tmetric 1\n")
+	found := false
+	for _, d := range diags {
+		if d.Message == "inconsistent indentation (mixed tabs and spaces) within eval block" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("diagnostics = %v, want the mixed-indentation diagnostic", messages(diags))
+	}
+}
+
+func TestLintTestDataCleanFileHasNoDiagnostics(t *testing.T) {
+	diags := lintContents(t, "load 5m\n  metric 1\n\neval instant at 1m metric\n  expect no_warn\n  metric 1\n")
+	if len(diags) != 0 {
+		t.Fatalf("diagnostics = %v, want none", messages(diags))
+	}
+}
+
+// A file that fails to parse must not hide diagnostics for every other file
+// in the directory: it should surface as its own diagnostic and let the scan
+// continue.
+func TestLintTestDataUnparseableFileDoesNotAbortScan(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "bad.test"), []byte("bogus_command foo\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "good.test"), []byte("eval instant at 1m metric\n  metric 1\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	diags, err := LintTestData(LintOptions{Dir: dir})
+	if err != nil {
+		t.Fatalf("LintTestData: %v", err)
+	}
+
+	var sawParseFailure, sawGoodFileDiagnostic bool
+	for _, d := range diags {
+		switch d.File {
+		case filepath.Join(dir, "bad.test"):
+			sawParseFailure = true
+		case filepath.Join(dir, "good.test"):
+			sawGoodFileDiagnostic = true
+		}
+	}
+	if !sawParseFailure {
+		t.Errorf("diagnostics = %v, want a diagnostic for the unparseable file", diags)
+	}
+	if !sawGoodFileDiagnostic {
+		t.Errorf("diagnostics = %v, want the good file to still be linted", diags)
+	}
+}
+
+// LintTestData must discover and lint .test.yaml files through LoadTestFile,
+// not just the bespoke .test text format.
+func TestLintTestDataDiscoversYAMLFiles(t *testing.T) {
+	dir := t.TempDir()
+	yamlContent := "blocks:\n" +
+		"- eval:\n" +
+		"    expr: metric\n" +
+		"    at: \"1m\"\n" +
+		"    expect:\n" +
+		"      fail:\n" +
+		"        msg: boom\n" +
+		"        regex: boo.*\n"
+	if err := os.WriteFile(filepath.Join(dir, "a.test.yaml"), []byte(yamlContent), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	diags, err := LintTestData(LintOptions{Dir: dir})
+	if err != nil {
+		t.Fatalf("LintTestData: %v", err)
+	}
+
+	found := false
+	for _, d := range diags {
+		if d.File == filepath.Join(dir, "a.test.yaml") && d.Message == "expect fail: msg: and regex: are mutually exclusive" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("diagnostics = %v, want the mutually-exclusive diagnostic for a.test.yaml", messages(diags))
+	}
+}
@@ -0,0 +1,88 @@
+package promqltest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+const migrateConfigFileName = ".promqltest-migrate.yaml"
+
+// MigrateDirConfig is the schema of a .promqltest-migrate.yaml file. Dropping
+// one into a testdata subdirectory overrides the migration behavior for
+// every .test file beneath it, the same way a .golangci.yml closer to a
+// package overrides one further up the tree.
+type MigrateDirConfig struct {
+	// Mode overrides the CLI-provided migration mode for files beneath this
+	// directory, e.g. "tolerant" when the repo default is "strict".
+	Mode string `yaml:"mode,omitempty"`
+	// ExtraExpectations adds expectation kinds to a deprecated command's
+	// output on top of whatever the effective mode already produces, keyed
+	// by command name (e.g. "eval_fail": ["no_info"]).
+	ExtraExpectations map[string][]string `yaml:"extra_expectations,omitempty"`
+}
+
+// loadMigrateDirConfig finds the nearest .promqltest-migrate.yaml ascending
+// from dir (inclusive) up to and including root, and parses it. It returns a
+// zero MigrateDirConfig if none is found.
+func loadMigrateDirConfig(root, dir string) (MigrateDirConfig, error) {
+	for {
+		path := filepath.Join(dir, migrateConfigFileName)
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			var cfg MigrateDirConfig
+			if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+				return MigrateDirConfig{}, fmt.Errorf("parsing %s: %w", path, err)
+			}
+			return cfg, nil
+		case !os.IsNotExist(err):
+			return MigrateDirConfig{}, fmt.Errorf("reading %s: %w", path, err)
+		}
+
+		if dir == root {
+			return MigrateDirConfig{}, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return MigrateDirConfig{}, nil
+		}
+		dir = parent
+	}
+}
+
+// resolveKindMapForFile computes the effective expectation-kind map for a
+// single file: the CLI-provided defaultMode, overridden by the nearest
+// .promqltest-migrate.yaml's mode (if any), with that config's
+// ExtraExpectations merged in on top.
+func resolveKindMapForFile(root, path string, defaultMode MigrateMode) (map[string][]string, error) {
+	cfg, err := loadMigrateDirConfig(root, filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+
+	mode := defaultMode
+	if cfg.Mode != "" {
+		m, err := ParseMigrateMode(cfg.Mode)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		mode = m
+	}
+
+	kindMap := expectationKindsByMode[mode]
+	if len(cfg.ExtraExpectations) == 0 {
+		return kindMap, nil
+	}
+
+	merged := make(map[string][]string, len(kindMap))
+	for command, kinds := range kindMap {
+		merged[command] = append([]string(nil), kinds...)
+	}
+	for command, extra := range cfg.ExtraExpectations {
+		merged[command] = append(merged[command], extra...)
+	}
+	return merged, nil
+}
@@ -0,0 +1,75 @@
+package promqltest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeMigrateConfig(t *testing.T, dir, contents string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, migrateConfigFileName), []byte(contents), 0o644); err != nil {
+		t.Fatalf("writing %s: %v", migrateConfigFileName, err)
+	}
+}
+
+func TestResolveKindMapForFileDefaultsToMode(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "a.test")
+
+	got, err := resolveKindMapForFile(root, path, MigrateStrict)
+	if err != nil {
+		t.Fatalf("resolveKindMapForFile: %v", err)
+	}
+	if !reflect.DeepEqual(got, expectationKindsByMode[MigrateStrict]) {
+		t.Fatalf("got %v, want the strict kind map unmodified", got)
+	}
+}
+
+func TestResolveKindMapForFileNearestConfigWins(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "sub")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+
+	writeMigrateConfig(t, root, "mode: tolerant\n")
+	writeMigrateConfig(t, sub, "mode: basic\n")
+
+	got, err := resolveKindMapForFile(root, filepath.Join(sub, "a.test"), MigrateStrict)
+	if err != nil {
+		t.Fatalf("resolveKindMapForFile: %v", err)
+	}
+	if !reflect.DeepEqual(got, expectationKindsByMode[MigrateBasic]) {
+		t.Fatalf("got %v, want the basic kind map (nearest config should win over root's)", got)
+	}
+}
+
+func TestResolveKindMapForFileMergesExtraExpectations(t *testing.T) {
+	root := t.TempDir()
+	writeMigrateConfig(t, root, "mode: basic\nextra_expectations:\n  eval_fail: [no_info]\n")
+
+	got, err := resolveKindMapForFile(root, filepath.Join(root, "a.test"), MigrateStrict)
+	if err != nil {
+		t.Fatalf("resolveKindMapForFile: %v", err)
+	}
+
+	want := append([]string(nil), expectationKindsByMode[MigrateBasic]["eval_fail"]...)
+	want = append(want, "no_info")
+	if !reflect.DeepEqual(got["eval_fail"], want) {
+		t.Fatalf("eval_fail kinds = %v, want %v", got["eval_fail"], want)
+	}
+}
+
+func TestResolveKindMapForFileNoConfig(t *testing.T) {
+	root := t.TempDir()
+
+	got, err := resolveKindMapForFile(root, filepath.Join(root, "sub", "a.test"), MigrateTolerant)
+	if err != nil {
+		t.Fatalf("resolveKindMapForFile: %v", err)
+	}
+	if !reflect.DeepEqual(got, expectationKindsByMode[MigrateTolerant]) {
+		t.Fatalf("got %v, want the tolerant kind map unmodified", got)
+	}
+}
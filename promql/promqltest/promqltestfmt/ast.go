@@ -0,0 +1,107 @@
+// Package promqltestfmt implements parsing and canonical formatting of
+// PromQL .test files, modeled on the way golang.org/x/mod/modfile separates
+// parsing a go.mod file from re-emitting it through Format.
+//
+// The typical use is Parse followed by some in-place editing of the
+// returned *File, followed by Format to obtain canonical bytes:
+//
+//	f, err := promqltestfmt.Parse(data)
+//	...
+//	out := promqltestfmt.Format(f)
+package promqltestfmt
+
+// File is the parsed form of a single .test file: a sequence of top-level
+// blocks in source order.
+type File struct {
+	Blocks []Block
+}
+
+// Block is implemented by LoadBlock, ClearBlock, EvalBlock, and CommentBlock.
+type Block interface {
+	block()
+}
+
+// CommentBlock is a run of one or more consecutive `#`-prefixed comment
+// lines at block-start position, such as a header documenting the test case
+// above it. Parse emits it as a block of its own, and Format writes the
+// block that follows it with no blank line in between, so that the common
+// "# what this tests\nload ...\n" style round-trips unchanged; a blank line
+// between a comment and the next block is dropped.
+type CommentBlock struct {
+	Lines []string
+
+	// Line is the 1-based source line of the first comment line.
+	Line int
+}
+
+func (*CommentBlock) block() {}
+
+// LoadBlock corresponds to a `load <step>` command and the indented series
+// samples that follow it.
+type LoadBlock struct {
+	Step  string
+	Lines []string
+
+	// Line is the 1-based source line of the `load` keyword, for callers
+	// (such as LintTestData) that need to report diagnostics against the
+	// original file.
+	Line int
+
+	// MixedIndent reports whether the block's body mixed tabs and spaces
+	// for indentation, detected once here rather than by every consumer
+	// re-scanning the raw lines.
+	MixedIndent bool
+}
+
+func (*LoadBlock) block() {}
+
+// ClearBlock corresponds to a standalone `clear` command.
+type ClearBlock struct {
+	// Line is the 1-based source line of the `clear` keyword.
+	Line int
+}
+
+func (*ClearBlock) block() {}
+
+// EvalBlock corresponds to an eval command and everything indented beneath
+// it: its expectations and its expected result-sample lines.
+//
+// Command preserves the verbatim command keyword as parsed ("eval",
+// "eval_fail", "eval_warn", "eval_info", or "eval_ordered") so that callers
+// migrating deprecated commands can tell what they started from. A fully
+// migrated block has Command == "eval" and its outcome expressed entirely
+// through Expectations.
+type EvalBlock struct {
+	Command string
+	Expr    string
+
+	Expectations []Expectation
+
+	// ExpectedFailMessage and ExpectedFailRegexp hold the deprecated
+	// `expected_fail_message`/`expected_fail_regexp` lines, if present, so
+	// that a migration step can fold them into a fail Expectation.
+	ExpectedFailMessage string
+	ExpectedFailRegexp  string
+
+	Samples []string
+
+	// Line is the 1-based source line of the command keyword.
+	Line int
+
+	// MixedIndent reports whether the block's body mixed tabs and spaces
+	// for indentation, detected once here rather than by every consumer
+	// re-scanning the raw lines.
+	MixedIndent bool
+}
+
+func (*EvalBlock) block() {}
+
+// Expectation is a single `expect <kind>` annotation attached to an
+// EvalBlock. Kind is one of "fail", "warn", "info", "ordered", "no_warn", or
+// "no_info". Msg and Regex are only set when Kind is "fail" and the
+// annotation carries a `msg:` or `regex:` qualifier.
+type Expectation struct {
+	Kind  string
+	Msg   string
+	Regex string
+}
@@ -0,0 +1,141 @@
+package promqltestfmt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// expectationOrder gives the canonical relative ordering of expect kinds
+// within an eval block: fail before no_warn before no_info, with the
+// remaining, mutually-exclusive kinds sorting ahead of both.
+var expectationOrder = map[string]int{
+	"fail":    0,
+	"warn":    0,
+	"info":    0,
+	"ordered": 0,
+	"no_warn": 1,
+	"no_info": 2,
+}
+
+// Format renders f using canonical indentation, annotation ordering, and
+// blank-line separation between blocks. The output is idempotent: formatting
+// an already-formatted file returns it unchanged.
+func Format(f *File) []byte {
+	var b strings.Builder
+	for i, blk := range f.Blocks {
+		if i > 0 {
+			// A CommentBlock documents the block right after it, so no blank
+			// line separates the two; a blank line between them in the
+			// source is not preserved.
+			if _, prevWasComment := f.Blocks[i-1].(*CommentBlock); !prevWasComment {
+				b.WriteString("\n")
+			}
+		}
+		writeBlock(&b, blk)
+	}
+	return []byte(b.String())
+}
+
+func writeBlock(b *strings.Builder, blk Block) {
+	switch v := blk.(type) {
+	case *CommentBlock:
+		for _, line := range v.Lines {
+			b.WriteString(line)
+			b.WriteString("\n")
+		}
+
+	case *LoadBlock:
+		fmt.Fprintf(b, "load %s\n", v.Step)
+		writeAlignedSamples(b, v.Lines)
+
+	case *ClearBlock:
+		b.WriteString("clear\n")
+
+	case *EvalBlock:
+		// Command is preserved verbatim rather than hardcoded to "eval":
+		// a block that still carries a deprecated command (eval_fail,
+		// eval_warn, ...) and has no explicit Expectations has not been
+		// migrated yet, and Format must not silently drop the assertion
+		// that command name carries by rewriting it to a bare eval.
+		// Converting deprecated commands into eval + expect annotations
+		// is transformAnnotations' job, not Format's.
+		fmt.Fprintf(b, "%s %s\n", v.Command, v.Expr)
+		for _, exp := range sortExpectations(v.Expectations) {
+			fmt.Fprintf(b, "  %s\n", formatExpectation(exp))
+		}
+		if v.ExpectedFailMessage != "" {
+			fmt.Fprintf(b, "  expected_fail_message %s\n", v.ExpectedFailMessage)
+		}
+		if v.ExpectedFailRegexp != "" {
+			fmt.Fprintf(b, "  expected_fail_regexp %s\n", v.ExpectedFailRegexp)
+		}
+		writeAlignedSamples(b, v.Samples)
+	}
+}
+
+// writeAlignedSamples writes each sample line indented and with its value
+// columns aligned: every line is split into a series-selector head (up to
+// and including a trailing "}", or the first field for a bare metric name)
+// and a value tail, and the tails are padded to start at a common column.
+// Splitting on "}" rather than on whitespace avoids misreading a space
+// inside a label matcher (e.g. `{a="b", c="d"}`) as a field separator.
+func writeAlignedSamples(b *strings.Builder, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+
+	heads := make([]string, len(lines))
+	tails := make([]string, len(lines))
+	width := 0
+	for i, line := range lines {
+		if strings.HasPrefix(line, "#") {
+			// An inline comment line has no value column to align; leave it
+			// untouched rather than misreading its text as a head/tail pair.
+			heads[i], tails[i] = line, ""
+			continue
+		}
+		heads[i], tails[i] = splitSeriesAndValues(line)
+		if tails[i] != "" && len(heads[i]) > width {
+			width = len(heads[i])
+		}
+	}
+
+	for i, line := range lines {
+		if tails[i] == "" {
+			fmt.Fprintf(b, "  %s\n", line)
+			continue
+		}
+		fmt.Fprintf(b, "  %-*s %s\n", width, heads[i], tails[i])
+	}
+}
+
+func splitSeriesAndValues(line string) (head, tail string) {
+	if idx := strings.LastIndex(line, "}"); idx >= 0 {
+		return line[:idx+1], strings.TrimSpace(line[idx+1:])
+	}
+	head, tail, ok := strings.Cut(strings.TrimSpace(line), " ")
+	if !ok {
+		return line, ""
+	}
+	return head, strings.TrimSpace(tail)
+}
+
+func sortExpectations(in []Expectation) []Expectation {
+	out := append([]Expectation(nil), in...)
+	sort.SliceStable(out, func(i, j int) bool {
+		return expectationOrder[out[i].Kind] < expectationOrder[out[j].Kind]
+	})
+	return out
+}
+
+func formatExpectation(e Expectation) string {
+	switch {
+	case e.Kind == "fail" && e.Msg != "":
+		return fmt.Sprintf("expect fail msg:%s", e.Msg)
+	case e.Kind == "fail" && e.Regex != "":
+		return fmt.Sprintf("expect fail regex:%s", e.Regex)
+	default:
+		return "expect " + e.Kind
+	}
+}
@@ -0,0 +1,105 @@
+package promqltestfmt
+
+import "testing"
+
+func TestParseFormatRoundTrip(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+	}{
+		{
+			name: "load and eval",
+			in: "load 5m\n" +
+				"  metric{a=\"b\"} 1 2 3\n" +
+				"\n" +
+				"eval instant at 1m metric{a=\"b\"}\n" +
+				"  metric{a=\"b\"} 3\n",
+		},
+		{
+			name: "clear",
+			in:   "clear\n",
+		},
+		{
+			name: "eval with expectations",
+			in: "eval instant at 1m sum(metric)\n" +
+				"  expect fail msg:boom\n" +
+				"  expect no_info\n",
+		},
+		{
+			name: "deprecated command preserved verbatim",
+			in: "eval_fail instant at 1m bad_metric()\n" +
+				"  bad_metric() 1\n",
+		},
+		{
+			name: "header comment attached to following block",
+			in: "# This is a comment\n" +
+				"load 5m\n" +
+				"  metric 1\n" +
+				"\n" +
+				"eval instant at 1m metric\n" +
+				"  expect no_warn\n" +
+				"  metric 1\n",
+		},
+		{
+			name: "multi-line comment and inline comment in block body",
+			in: "# line one\n" +
+				"# line two\n" +
+				"eval instant at 1m metric\n" +
+				"  expect no_warn\n" +
+				"  # inline comment\n" +
+				"  metric 1\n",
+		},
+		{
+			name: "standalone comment block",
+			in: "clear\n" +
+				"\n" +
+				"# a trailing note, not attached to anything after it\n",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := Parse([]byte(tc.in))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			out := string(Format(f))
+			if out != tc.in {
+				t.Fatalf("Format(Parse(in)) mismatch:\ngot:\n%s\nwant:\n%s", out, tc.in)
+			}
+
+			// Formatting an already-formatted file must be a no-op.
+			f2, err := Parse([]byte(out))
+			if err != nil {
+				t.Fatalf("Parse(Format(...)): %v", err)
+			}
+			if out2 := string(Format(f2)); out2 != out {
+				t.Fatalf("Format is not idempotent:\nfirst:\n%s\nsecond:\n%s", out, out2)
+			}
+		})
+	}
+}
+
+func TestFormatPreservesDeprecatedCommand(t *testing.T) {
+	f, err := Parse([]byte("eval_fail instant at 1m foo\n  bar 1\n"))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	got := string(Format(f))
+	want := "eval_fail instant at 1m foo\n  bar 1\n"
+	if got != want {
+		t.Fatalf("Format rewrote deprecated command: got %q, want %q", got, want)
+	}
+}
+
+func TestFormatAlignsSampleColumns(t *testing.T) {
+	f, err := Parse([]byte("load 5m\n  short 1\n  metric{a=\"b\"} 2\n"))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	got := string(Format(f))
+	want := "load 5m\n  short         1\n  metric{a=\"b\"} 2\n"
+	if got != want {
+		t.Fatalf("sample columns not aligned:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
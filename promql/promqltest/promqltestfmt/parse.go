@@ -0,0 +1,147 @@
+package promqltestfmt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/grafana/regexp"
+)
+
+var commandRegex = regexp.MustCompile(`^(eval|eval_fail|eval_warn|eval_info|eval_ordered)\s+(.*)$`)
+
+// Parse parses the contents of a .test file into a *File.
+func Parse(data []byte) (*File, error) {
+	lines := strings.Split(string(data), "\n")
+	f := &File{}
+
+	for i := 0; i < len(lines); {
+		trimmed := strings.TrimSpace(lines[i])
+
+		switch {
+		case trimmed == "":
+			i++
+
+		case strings.HasPrefix(trimmed, "#"):
+			startLine := i + 1
+			var comment []string
+			for i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), "#") {
+				comment = append(comment, strings.TrimSpace(lines[i]))
+				i++
+			}
+			f.Blocks = append(f.Blocks, &CommentBlock{Lines: comment, Line: startLine})
+
+		case trimmed == "clear":
+			f.Blocks = append(f.Blocks, &ClearBlock{Line: i + 1})
+			i++
+
+		case strings.HasPrefix(trimmed, "load"):
+			step := strings.TrimSpace(strings.TrimPrefix(trimmed, "load"))
+			startLine := i + 1
+			i++
+			var body []string
+			mixed := newMixedIndentTracker()
+			for i < len(lines) && isIndented(lines[i]) {
+				mixed.see(lines[i])
+				body = append(body, strings.TrimSpace(lines[i]))
+				i++
+			}
+			f.Blocks = append(f.Blocks, &LoadBlock{Step: step, Lines: body, Line: startLine, MixedIndent: mixed.mixed()})
+
+		default:
+			matches := commandRegex.FindStringSubmatch(trimmed)
+			if matches == nil {
+				return nil, fmt.Errorf("line %d: unrecognized command %q", i+1, trimmed)
+			}
+			block, next, err := parseEvalBlock(lines, i, matches[1], matches[2])
+			if err != nil {
+				return nil, err
+			}
+			f.Blocks = append(f.Blocks, block)
+			i = next
+		}
+	}
+
+	return f, nil
+}
+
+func parseEvalBlock(lines []string, start int, command, expr string) (*EvalBlock, int, error) {
+	eb := &EvalBlock{Command: command, Expr: expr, Line: start + 1}
+	i := start + 1
+	mixed := newMixedIndentTracker()
+	for i < len(lines) && isIndented(lines[i]) {
+		mixed.see(lines[i])
+		trimmed := strings.TrimSpace(lines[i])
+		switch {
+		case strings.HasPrefix(trimmed, "expect "):
+			exp, err := parseExpectation(trimmed)
+			if err != nil {
+				return nil, 0, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			eb.Expectations = append(eb.Expectations, exp)
+		case strings.HasPrefix(trimmed, "expected_fail_message"):
+			eb.ExpectedFailMessage = strings.TrimSpace(strings.TrimPrefix(trimmed, "expected_fail_message"))
+		case strings.HasPrefix(trimmed, "expected_fail_regexp"):
+			eb.ExpectedFailRegexp = strings.TrimSpace(strings.TrimPrefix(trimmed, "expected_fail_regexp"))
+		default:
+			eb.Samples = append(eb.Samples, trimmed)
+		}
+		i++
+	}
+	eb.MixedIndent = mixed.mixed()
+	return eb, i, nil
+}
+
+// mixedIndentTracker flags a block whose body indents some lines with tabs
+// and others with spaces, which renders inconsistently across editors.
+type mixedIndentTracker struct {
+	sawTab, sawSpace bool
+}
+
+func newMixedIndentTracker() *mixedIndentTracker {
+	return &mixedIndentTracker{}
+}
+
+func (m *mixedIndentTracker) see(line string) {
+	indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+	if strings.Contains(indent, "\t") {
+		m.sawTab = true
+	}
+	if strings.Contains(indent, " ") {
+		m.sawSpace = true
+	}
+}
+
+func (m *mixedIndentTracker) mixed() bool {
+	return m.sawTab && m.sawSpace
+}
+
+func parseExpectation(line string) (Expectation, error) {
+	rest := strings.TrimSpace(strings.TrimPrefix(line, "expect"))
+	kind, tail, _ := strings.Cut(rest, " ")
+	tail = strings.TrimSpace(tail)
+
+	switch kind {
+	case "fail":
+		switch {
+		case tail == "":
+			return Expectation{Kind: kind}, nil
+		case strings.HasPrefix(tail, "msg:"):
+			return Expectation{Kind: kind, Msg: strings.TrimPrefix(tail, "msg:")}, nil
+		case strings.HasPrefix(tail, "regex:"):
+			return Expectation{Kind: kind, Regex: strings.TrimPrefix(tail, "regex:")}, nil
+		default:
+			return Expectation{}, fmt.Errorf("invalid qualifier %q for expect fail", tail)
+		}
+	case "warn", "info", "ordered", "no_warn", "no_info":
+		if tail != "" {
+			return Expectation{}, fmt.Errorf("expect %s takes no qualifier, got %q", kind, tail)
+		}
+		return Expectation{Kind: kind}, nil
+	default:
+		return Expectation{}, fmt.Errorf("unknown expect kind %q", kind)
+	}
+}
+
+func isIndented(line string) bool {
+	return line != "" && (line[0] == ' ' || line[0] == '\t')
+}
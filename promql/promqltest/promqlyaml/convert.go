@@ -0,0 +1,138 @@
+package promqlyaml
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/prometheus/promql/promqltest/promqltestfmt"
+)
+
+// ToFile converts doc to the same *promqltestfmt.File AST the text parser
+// produces, so that the two formats share one code path for formatting and
+// migration.
+func (doc *Document) ToFile() (*promqltestfmt.File, error) {
+	f := &promqltestfmt.File{}
+	for i, blk := range doc.Blocks {
+		switch {
+		case blk.Load != nil && blk.Clear == nil && blk.Eval == nil:
+			f.Blocks = append(f.Blocks, &promqltestfmt.LoadBlock{
+				Step:  blk.Load.Step,
+				Lines: append([]string(nil), blk.Load.Series...),
+			})
+
+		case blk.Clear != nil && blk.Load == nil && blk.Eval == nil:
+			f.Blocks = append(f.Blocks, &promqltestfmt.ClearBlock{})
+
+		case blk.Eval != nil && blk.Load == nil && blk.Clear == nil:
+			eb := &promqltestfmt.EvalBlock{
+				Command: "eval",
+				Expr:    evalExpr(blk.Eval),
+				Samples: append([]string(nil), blk.Eval.Samples...),
+			}
+			if blk.Eval.Expect != nil {
+				eb.Expectations = expectationsFromDoc(blk.Eval.Expect)
+			}
+			f.Blocks = append(f.Blocks, eb)
+
+		default:
+			return nil, fmt.Errorf("block %d: must set exactly one of load, clear, eval", i)
+		}
+	}
+	return f, nil
+}
+
+// FromFile converts a *promqltestfmt.File into its YAML Document form.
+func FromFile(f *promqltestfmt.File) *Document {
+	doc := &Document{}
+	for _, blk := range f.Blocks {
+		switch v := blk.(type) {
+		case *promqltestfmt.LoadBlock:
+			doc.Blocks = append(doc.Blocks, BlockDoc{Load: &LoadDoc{
+				Step:   v.Step,
+				Series: append([]string(nil), v.Lines...),
+			}})
+
+		case *promqltestfmt.ClearBlock:
+			doc.Blocks = append(doc.Blocks, BlockDoc{Clear: &struct{}{}})
+
+		case *promqltestfmt.EvalBlock:
+			at, expr := stripAtPrefix(v.Expr)
+			doc.Blocks = append(doc.Blocks, BlockDoc{Eval: &EvalDoc{
+				Expr:    expr,
+				At:      at,
+				Expect:  expectDocFromExpectations(v.Expectations),
+				Samples: append([]string(nil), v.Samples...),
+			}})
+		}
+	}
+	return doc
+}
+
+func evalExpr(e *EvalDoc) string {
+	if e.At == "" {
+		return e.Expr
+	}
+	return fmt.Sprintf("instant at %s %s", e.At, e.Expr)
+}
+
+func expectationsFromDoc(e *ExpectDoc) []promqltestfmt.Expectation {
+	var out []promqltestfmt.Expectation
+	if e.Fail != nil {
+		out = append(out, promqltestfmt.Expectation{Kind: "fail", Msg: e.Fail.Msg, Regex: e.Fail.Regex})
+	}
+	if e.Warn {
+		out = append(out, promqltestfmt.Expectation{Kind: "warn"})
+	}
+	if e.Info {
+		out = append(out, promqltestfmt.Expectation{Kind: "info"})
+	}
+	if e.Ordered {
+		out = append(out, promqltestfmt.Expectation{Kind: "ordered"})
+	}
+	if e.NoWarn {
+		out = append(out, promqltestfmt.Expectation{Kind: "no_warn"})
+	}
+	if e.NoInfo {
+		out = append(out, promqltestfmt.Expectation{Kind: "no_info"})
+	}
+	return out
+}
+
+func expectDocFromExpectations(exps []promqltestfmt.Expectation) *ExpectDoc {
+	if len(exps) == 0 {
+		return nil
+	}
+	e := &ExpectDoc{}
+	for _, exp := range exps {
+		switch exp.Kind {
+		case "fail":
+			e.Fail = &FailDoc{Msg: exp.Msg, Regex: exp.Regex}
+		case "warn":
+			e.Warn = true
+		case "info":
+			e.Info = true
+		case "ordered":
+			e.Ordered = true
+		case "no_warn":
+			e.NoWarn = true
+		case "no_info":
+			e.NoInfo = true
+		}
+	}
+	return e
+}
+
+// stripAtPrefix removes a leading "instant at <duration> " from expr, for
+// callers that want the bare expression and duration split back apart.
+func stripAtPrefix(expr string) (at, bare string) {
+	const prefix = "instant at "
+	if !strings.HasPrefix(expr, prefix) {
+		return "", expr
+	}
+	rest := strings.TrimPrefix(expr, prefix)
+	at, bare, ok := strings.Cut(rest, " ")
+	if !ok {
+		return "", expr
+	}
+	return at, bare
+}
@@ -0,0 +1,57 @@
+package promqlyaml
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/prometheus/promql/promqltest/promqltestfmt"
+)
+
+func TestToFileFromFileRoundTrip(t *testing.T) {
+	doc := &Document{
+		Blocks: []BlockDoc{
+			{Load: &LoadDoc{Step: "5m", Series: []string{"metric{a=\"b\"} 1 2 3"}}},
+			{Clear: &struct{}{}},
+			{Eval: &EvalDoc{
+				Expr:    "sum(metric)",
+				At:      "1m",
+				Expect:  &ExpectDoc{Fail: &FailDoc{Msg: "boom"}},
+				Samples: []string{"{} 1"},
+			}},
+		},
+	}
+
+	f, err := doc.ToFile()
+	if err != nil {
+		t.Fatalf("ToFile: %v", err)
+	}
+
+	got := FromFile(f)
+	if !reflect.DeepEqual(got, doc) {
+		t.Fatalf("FromFile(ToFile(doc)) mismatch:\ngot:  %+v\nwant: %+v", got, doc)
+	}
+}
+
+func TestToFileRejectsAmbiguousBlock(t *testing.T) {
+	doc := &Document{Blocks: []BlockDoc{{Load: &LoadDoc{Step: "5m"}, Clear: &struct{}{}}}}
+	if _, err := doc.ToFile(); err == nil {
+		t.Fatal("expected an error for a block that sets more than one of load/clear/eval")
+	}
+}
+
+func TestToFileEvalExprPrependsAtPrefix(t *testing.T) {
+	doc := &Document{Blocks: []BlockDoc{
+		{Eval: &EvalDoc{Expr: "foo", At: "1m"}},
+	}}
+	f, err := doc.ToFile()
+	if err != nil {
+		t.Fatalf("ToFile: %v", err)
+	}
+	eb, ok := f.Blocks[0].(*promqltestfmt.EvalBlock)
+	if !ok {
+		t.Fatalf("expected *promqltestfmt.EvalBlock, got %T", f.Blocks[0])
+	}
+	if want := "instant at 1m foo"; eb.Expr != want {
+		t.Fatalf("Expr = %q, want %q", eb.Expr, want)
+	}
+}
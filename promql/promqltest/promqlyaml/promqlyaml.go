@@ -0,0 +1,75 @@
+// Package promqlyaml defines a structured, YAML-based alternative to the
+// bespoke .test syntax parsed by promqltestfmt, for callers that want to
+// generate PromQL test cases programmatically rather than hand-writing the
+// text format. A Document round-trips through promqltestfmt.File, so the
+// same eval/load/clear semantics apply to both representations.
+package promqlyaml
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Document is the top-level shape of a .test.yaml file: an ordered list of
+// blocks, each of which sets exactly one of Load, Clear, or Eval.
+type Document struct {
+	Blocks []BlockDoc `yaml:"blocks"`
+}
+
+// BlockDoc is a single entry in Document.Blocks. Exactly one field should be
+// set; ToFile rejects blocks that set zero or more than one.
+type BlockDoc struct {
+	Load  *LoadDoc  `yaml:"load,omitempty"`
+	Clear *struct{} `yaml:"clear,omitempty"`
+	Eval  *EvalDoc  `yaml:"eval,omitempty"`
+}
+
+// LoadDoc is the YAML form of a `load` block.
+type LoadDoc struct {
+	Step   string   `yaml:"step"`
+	Series []string `yaml:"series"`
+}
+
+// EvalDoc is the YAML form of an `eval` block. Expr carries the expression
+// exactly as it would appear after `eval `/`eval instant at <At> ` in the
+// text format; At is split out as its own field purely for readability and
+// is prepended as `instant at <At>` when converting to the text AST.
+type EvalDoc struct {
+	Expr    string     `yaml:"expr"`
+	At      string     `yaml:"at,omitempty"`
+	Expect  *ExpectDoc `yaml:"expect,omitempty"`
+	Samples []string   `yaml:"samples,omitempty"`
+}
+
+// ExpectDoc is the YAML form of the `expect ...` annotations attached to an
+// eval block. Fail carries the optional msg/regex qualifier; the rest are
+// plain booleans mirroring the annotation names.
+type ExpectDoc struct {
+	Fail    *FailDoc `yaml:"fail,omitempty"`
+	Warn    bool     `yaml:"warn,omitempty"`
+	Info    bool     `yaml:"info,omitempty"`
+	Ordered bool     `yaml:"ordered,omitempty"`
+	NoWarn  bool     `yaml:"no_warn,omitempty"`
+	NoInfo  bool     `yaml:"no_info,omitempty"`
+}
+
+// FailDoc qualifies an `expect fail` with exactly one of Msg or Regex.
+type FailDoc struct {
+	Msg   string `yaml:"msg,omitempty"`
+	Regex string `yaml:"regex,omitempty"`
+}
+
+// Parse decodes a .test.yaml document.
+func Parse(data []byte) (*Document, error) {
+	var doc Document
+	if err := yaml.UnmarshalStrict(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing yaml test document: %w", err)
+	}
+	return &doc, nil
+}
+
+// Marshal encodes a Document back to YAML.
+func Marshal(doc *Document) ([]byte, error) {
+	return yaml.Marshal(doc)
+}
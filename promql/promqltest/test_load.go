@@ -0,0 +1,41 @@
+package promqltest
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/prometheus/prometheus/promql/promqltest/promqltestfmt"
+	"github.com/prometheus/prometheus/promql/promqltest/promqlyaml"
+)
+
+// LoadTestFile reads a PromQL test file and parses it into a
+// *promqltestfmt.File, auto-detecting the format from its extension: files
+// ending in ".test.yaml" are decoded as promqlyaml.Document and converted to
+// the text AST; everything else is parsed with the bespoke .test syntax.
+// The runner can drive either form identically once loaded, since both
+// produce the same AST.
+func LoadTestFile(path string) (*promqltestfmt.File, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+
+	if strings.HasSuffix(path, ".test.yaml") {
+		doc, err := promqlyaml.Parse(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse yaml test file %s: %w", path, err)
+		}
+		f, err := doc.ToFile()
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert yaml test file %s: %w", path, err)
+		}
+		return f, nil
+	}
+
+	f, err := promqltestfmt.Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse test file %s: %w", path, err)
+	}
+	return f, nil
+}
@@ -1,14 +1,48 @@
 package promqltest
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
 
-	"github.com/grafana/regexp"
+	"github.com/prometheus/prometheus/promql/promqltest/promqltestfmt"
+	"github.com/prometheus/prometheus/promql/promqltest/promqlyaml"
 )
 
+// MigrateParallelism bounds how many .test files MigrateTestData and
+// PreviewMigrateTestData process concurrently. It defaults to
+// runtime.NumCPU() and may be overridden by callers, mirroring the -n flag
+// of Go's test/run.go.
+var MigrateParallelism = runtime.NumCPU()
+
+// FileMigrationPreview describes the effect that MigrateTestData would have on
+// a single .test file without modifying it on disk.
+type FileMigrationPreview struct {
+	// Path is the file that would be migrated, relative to the repository root.
+	Path string
+	// Diff is a unified diff of the old file contents against the migrated
+	// contents. It is empty if the file would not change.
+	Diff string
+	// BlocksTouched is the number of eval blocks rewritten to the new annotation syntax.
+	BlocksTouched int
+	// BlocksSkipped is the number of eval blocks left untouched because they already
+	// contain an `expect` annotation.
+	BlocksSkipped int
+	// BlocksFailed is the number of eval blocks that could not be rewritten.
+	BlocksFailed int
+	// YAMLPath is the path of the .test.yaml sibling written alongside Path,
+	// set only when the migration's MigrateEmit produces a YAML form.
+	YAMLPath string
+	// YAMLDiff is a unified diff of the existing YAML sibling (if any)
+	// against its migrated contents, set only when YAMLPath is set.
+	YAMLDiff string
+}
+
 type MigrateMode int
 
 const (
@@ -30,144 +64,405 @@ func ParseMigrateMode(s string) (MigrateMode, error) {
 	}
 }
 
+// MigrateEmit selects which file format(s) MigrateTestData produces for each
+// migrated test case.
+type MigrateEmit int
+
+const (
+	// EmitText rewrites the .test file in place. This is the default.
+	EmitText MigrateEmit = iota
+	// EmitYAML writes a .test.yaml sibling instead of rewriting the .test file.
+	EmitYAML
+	// EmitBoth rewrites the .test file and writes a .test.yaml sibling.
+	EmitBoth
+)
+
+// ParseMigrateEmit parses the --emit flag value ("text", "yaml", or "both").
+// An empty string means "text", matching the default.
+func ParseMigrateEmit(s string) (MigrateEmit, error) {
+	switch s {
+	case "", "text":
+		return EmitText, nil
+	case "yaml":
+		return EmitYAML, nil
+	case "both":
+		return EmitBoth, nil
+	default:
+		return EmitText, fmt.Errorf("invalid emit: %s", s)
+	}
+}
+
+// expectationKindsByMode maps each MigrateMode to the set of expectation
+// kinds that a deprecated eval command is rewritten into, keyed by the
+// command's verbatim name as parsed by promqltestfmt (e.g. "eval_fail").
+var expectationKindsByMode = map[MigrateMode]map[string][]string{
+	MigrateStrict: {
+		"eval_fail":    {"fail", "no_warn", "no_info"},
+		"eval_warn":    {"warn", "no_info"},
+		"eval_info":    {"info", "no_warn"},
+		"eval_ordered": {"ordered", "no_warn", "no_info"},
+		"eval":         {"no_warn", "no_info"},
+	},
+	MigrateBasic: {
+		"eval_fail":    {"fail"},
+		"eval_warn":    {"warn"},
+		"eval_info":    {"info"},
+		"eval_ordered": {"ordered"},
+	},
+	MigrateTolerant: {
+		"eval_fail":    {"fail"},
+		"eval_ordered": {"ordered"},
+	},
+}
+
+// defaultTestDataDir is the testdata tree MigrateTestData and
+// PreviewMigrateTestData operate on when a caller doesn't name a directory
+// explicitly. It's relative to the repository root, which is where the CLI
+// built on top of these functions (cmd/promql-migrate-testdata) is meant to
+// be run from.
+const defaultTestDataDir = "promql/promqltest/testdata"
+
 // MigrateTestData migrates all PromQL test files to the new syntax format.
 // It applies annotation rules based on the provided migration mode ("strict", "basic", or "tolerant").
 // The function parses each .test file, converts it to the new syntax and overwrites the file.
 func MigrateTestData(mode string) error {
-	const dir = "promql/promqltest/testdata"
+	_, err := migrateTestData(defaultTestDataDir, mode, EmitText, true)
+	return err
+}
+
+// MigrateTestDataEmit behaves like MigrateTestData but additionally accepts
+// an emit mode ("text", "yaml", or "both") controlling whether the .test
+// file is rewritten in place, a .test.yaml sibling is produced, or both.
+func MigrateTestDataEmit(mode, emit string) error {
+	migrateEmit, err := ParseMigrateEmit(emit)
+	if err != nil {
+		return fmt.Errorf("failed to parse emit: %w", err)
+	}
+	_, err = migrateTestData(defaultTestDataDir, mode, migrateEmit, true)
+	return err
+}
+
+// PreviewMigrateTestData computes what MigrateTestData would change for the
+// given migration mode without writing anything to disk. It returns one
+// FileMigrationPreview per .test file that would be modified, so that the
+// result can be reviewed (e.g. printed as a diff in CI) before committing to
+// a destructive rewrite.
+func PreviewMigrateTestData(mode string) ([]FileMigrationPreview, error) {
+	return migrateTestData(defaultTestDataDir, mode, EmitText, false)
+}
+
+// PreviewMigrateTestDataEmit behaves like PreviewMigrateTestData but
+// additionally accepts an emit mode ("text", "yaml", or "both"), so that a
+// --emit=yaml or --emit=both migration can be reviewed via
+// FileMigrationPreview.YAMLDiff before MigrateTestDataEmit is run for real.
+func PreviewMigrateTestDataEmit(mode, emit string) ([]FileMigrationPreview, error) {
+	migrateEmit, err := ParseMigrateEmit(emit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse emit: %w", err)
+	}
+	return migrateTestData(defaultTestDataDir, mode, migrateEmit, false)
+}
+
+// migrateFileResult is the outcome of migrating a single file, produced by a
+// migrateTestData worker and collected back on the calling goroutine.
+type migrateFileResult struct {
+	preview FileMigrationPreview
+	err     error
+}
+
+// migrateTestData processes every .test file under dir with a worker pool
+// sized by MigrateParallelism. Files are read, parsed, and (optionally)
+// written independently, so per-file errors are collected into a single
+// joined error rather than aborting the remaining files. Since os.ReadDir
+// already returns entries sorted by filename, and each worker's result is
+// written back to a slot indexed by that sorted order, the returned preview
+// order is deterministic regardless of how the work happens to schedule.
+//
+// dir is taken as a parameter rather than hardcoded so that callers running
+// outside the repository root (such as a benchmark, which go test runs from
+// the package directory) can point it at a fixture instead.
+func migrateTestData(dir, mode string, emit MigrateEmit, write bool) ([]FileMigrationPreview, error) {
 	migrationMode, err := ParseMigrateMode(mode)
 	if err != nil {
-		return fmt.Errorf("failed to parse mode: %w", err)
+		return nil, fmt.Errorf("failed to parse mode: %w", err)
 	}
 
-	files, err := os.ReadDir(dir)
+	var paths []string
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || d.Name() == migrateConfigFileName || !strings.HasSuffix(path, ".test") {
+			return nil
+		}
+		paths = append(paths, path)
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to read testdata directory: %w", err)
-	}
-
-	annotationMap := map[MigrateMode]map[string][]string{
-		MigrateStrict: {
-			"eval_fail":    {"expect fail", "expect no_warn", "expect no_info"},
-			"eval_warn":    {"expect warn", "expect no_info"},
-			"eval_info":    {"expect info", "expect no_warn"},
-			"eval_ordered": {"expect ordered", "expect no_warn", "expect no_info"},
-			"eval":         {"expect no_warn", "expect no_info"},
-		},
-		MigrateBasic: {
-			"eval_fail":    {"expect fail"},
-			"eval_warn":    {"expect warn"},
-			"eval_info":    {"expect info"},
-			"eval_ordered": {"expect ordered"},
-		},
-		MigrateTolerant: {
-			"eval_fail":    {"expect fail"},
-			"eval_ordered": {"expect ordered"},
-		},
-	}
-
-	evalRegex := regexp.MustCompile(`^(eval |eval_fail |eval_warn |eval_info |eval_ordered )(.*)$`)
-
-	for _, file := range files {
-		if file.IsDir() || !strings.HasSuffix(file.Name(), ".test") {
+		return nil, fmt.Errorf("failed to walk testdata directory: %w", err)
+	}
+
+	results := make([]migrateFileResult, len(paths))
+
+	workers := MigrateParallelism
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(paths) {
+		workers = len(paths)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				results[idx] = migrateFile(dir, paths[idx], migrationMode, emit, write)
+			}
+		}()
+	}
+	for idx := range paths {
+		jobs <- idx
+	}
+	close(jobs)
+	wg.Wait()
+
+	var previews []FileMigrationPreview
+	var errs []error
+	for _, res := range results {
+		if res.err != nil {
+			errs = append(errs, res.err)
 			continue
 		}
+		if !write && (res.preview.Diff != "" || res.preview.YAMLDiff != "") {
+			previews = append(previews, res.preview)
+		}
+	}
+	if len(errs) > 0 {
+		return previews, errors.Join(errs...)
+	}
+	return previews, nil
+}
 
-		path := filepath.Join(dir, file.Name())
-		content, err := os.ReadFile(path)
-		if err != nil {
-			return fmt.Errorf("failed to read file %s: %w", path, err)
+func migrateFile(root, path string, defaultMode MigrateMode, emit MigrateEmit, write bool) migrateFileResult {
+	kindMap, err := resolveKindMapForFile(root, path, defaultMode)
+	if err != nil {
+		return migrateFileResult{err: fmt.Errorf("failed to resolve migration config for %s: %w", path, err)}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return migrateFileResult{err: fmt.Errorf("failed to read file %s: %w", path, err)}
+	}
+
+	parsed, err := promqltestfmt.Parse(content)
+	if err != nil {
+		return migrateFileResult{err: fmt.Errorf("error processing file %s: %w", path, err)}
+	}
+
+	stats := transformAnnotations(parsed, kindMap)
+	newContent := promqltestfmt.Format(parsed)
+
+	preview := FileMigrationPreview{
+		Path:          path,
+		BlocksTouched: stats.touched,
+		BlocksSkipped: stats.skipped,
+		BlocksFailed:  stats.failed,
+	}
+	changed := string(newContent) != string(content)
+	// Only populate Diff when this emit mode would actually rewrite path:
+	// an EmitYAML-only run never touches the .test file, so a text diff
+	// here would tell a -preview caller a file "would change" when the
+	// subsequent real run can't possibly change it.
+	if changed && (emit == EmitText || emit == EmitBoth) {
+		preview.Diff = unifiedDiff(path, strings.Split(string(content), "\n"), strings.Split(string(newContent), "\n"))
+	}
+
+	if (emit == EmitText || emit == EmitBoth) && write && changed {
+		if err := os.WriteFile(path, newContent, 0o644); err != nil {
+			return migrateFileResult{err: fmt.Errorf("failed to write file %s: %w", path, err)}
 		}
+	}
 
-		lines := strings.Split(string(content), "\n")
-		processedLines, err := processTestFileLines(lines, annotationMap[migrationMode], evalRegex)
+	if emit == EmitYAML || emit == EmitBoth {
+		yamlPath := path + ".yaml"
+		yamlContent, err := promqlyaml.Marshal(promqlyaml.FromFile(parsed))
 		if err != nil {
-			return fmt.Errorf("error processing file %s: %w", path, err)
+			return migrateFileResult{err: fmt.Errorf("failed to marshal yaml for %s: %w", path, err)}
 		}
 
-		if err := os.WriteFile(path, []byte(strings.Join(processedLines, "\n")), 0o644); err != nil {
-			return fmt.Errorf("failed to write file %s: %w", path, err)
+		existing, err := os.ReadFile(yamlPath)
+		if err != nil && !os.IsNotExist(err) {
+			return migrateFileResult{err: fmt.Errorf("failed to read file %s: %w", yamlPath, err)}
+		}
+
+		preview.YAMLPath = yamlPath
+		if string(yamlContent) != string(existing) {
+			preview.YAMLDiff = unifiedDiff(yamlPath, strings.Split(string(existing), "\n"), strings.Split(string(yamlContent), "\n"))
+			if write {
+				if err := os.WriteFile(yamlPath, yamlContent, 0o644); err != nil {
+					return migrateFileResult{err: fmt.Errorf("failed to write file %s: %w", yamlPath, err)}
+				}
+			}
 		}
 	}
-	return nil
+
+	return migrateFileResult{preview: preview}
+}
+
+// migrateBlockStats summarizes what transformAnnotations did to the eval
+// blocks in a single file.
+type migrateBlockStats struct {
+	touched int
+	skipped int
+	failed  int
 }
 
-func processTestFileLines(
-	lines []string,
-	annotationMap map[string][]string,
-	evalRegex *regexp.Regexp,
-) (result []string, err error) {
-	var inputBlock []string
-	var outputBlock []string
-	for i := 0; i < len(lines); i += len(inputBlock) {
-		inputBlock = nil
-		outputBlock = nil
-		matches := evalRegex.FindStringSubmatch(strings.TrimSpace(lines[i]))
-		if matches == nil {
-			inputBlock = append(inputBlock, lines[i])
-			result = append(result, lines[i])
+// transformAnnotations rewrites every EvalBlock in f that still uses a
+// deprecated command (eval_fail, eval_warn, eval_info, eval_ordered) or a
+// deprecated expected_fail_message/expected_fail_regexp line into the
+// canonical form: command "eval" plus explicit `expect` annotations. Blocks
+// that already carry an explicit expectation are left untouched, on the
+// assumption that a human already migrated (or deliberately overrode) them.
+func transformAnnotations(f *promqltestfmt.File, kindMap map[string][]string) migrateBlockStats {
+	var stats migrateBlockStats
+	for _, blk := range f.Blocks {
+		eb, ok := blk.(*promqltestfmt.EvalBlock)
+		if !ok {
 			continue
 		}
 
-		skipBlock := false
-		for j := i + 1; j < len(lines) && !evalRegex.MatchString(strings.TrimSpace(lines[j])); j++ {
-			inputBlock = append(inputBlock, lines[j])
-			if strings.Contains(lines[j], "expect ") {
-				skipBlock = true
-			}
+		if len(eb.Expectations) > 0 {
+			stats.skipped++
+			continue
 		}
 
-		if skipBlock {
-			result = append(result, lines[i])
-			i++
-			result = append(result, inputBlock...)
+		// A block that sets both the deprecated expected_fail_message and
+		// expected_fail_regexp lines is ambiguous: expect fail allows only
+		// one of msg:/regex:, and there's no principled way to pick a
+		// winner on the block's behalf. Leave it untouched (Format will
+		// still emit it verbatim, deprecated command and all) rather than
+		// silently resolving the conflict.
+		if eb.ExpectedFailMessage != "" && eb.ExpectedFailRegexp != "" {
+			stats.failed++
 			continue
 		}
 
-		// Detecting indentation style (tab or space) from the first non-empty, indented line
-		indent := "  "
-		for _, line := range inputBlock {
-			trimmed := strings.TrimLeft(line, " \t")
-			if len(trimmed) < len(line) {
-				indent = line[:len(line)-len(trimmed)]
-				break
+		for _, kind := range kindMap[eb.Command] {
+			eb.Expectations = append(eb.Expectations, promqltestfmt.Expectation{Kind: kind})
+		}
+		for i := range eb.Expectations {
+			if eb.Expectations[i].Kind != "fail" {
+				continue
+			}
+			if eb.ExpectedFailMessage != "" {
+				eb.Expectations[i].Msg = eb.ExpectedFailMessage
+			}
+			if eb.ExpectedFailRegexp != "" {
+				eb.Expectations[i].Regex = eb.ExpectedFailRegexp
 			}
 		}
+		eb.ExpectedFailMessage = ""
+		eb.ExpectedFailRegexp = ""
+		eb.Command = "eval"
+		stats.touched++
+	}
+	return stats
+}
+
+// diffOpKind identifies the kind of a single line in a diffOp.
+type diffOpKind int
+
+const (
+	diffEqual diffOpKind = iota
+	diffDelete
+	diffInsert
+)
 
-		command := strings.TrimSpace(matches[1])
-		expression := matches[2]
-		var annotations []string
-		result = append(result, fmt.Sprintf("eval %s", expression))
-		i++
+type diffOp struct {
+	kind diffOpKind
+	line string
+}
+
+// unifiedDiff renders a unified diff of oldLines against newLines: the
+// `--- a/path` and `+++ b/path` headers of `diff -u`, followed by every line
+// prefixed with ' ', '-' or '+'. It writes no @@ hunk headers and does not
+// trim unchanged lines down to context.
+func unifiedDiff(path string, oldLines, newLines []string) string {
+	ops := diffLines(oldLines, newLines)
+
+	changed := false
+	for _, op := range ops {
+		if op.kind != diffEqual {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return ""
+	}
 
-		for _, annotation := range annotationMap[command] {
-			annotations = append(annotations, indent+annotation)
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- a/%s\n", path)
+	fmt.Fprintf(&b, "+++ b/%s\n", path)
+	for _, op := range ops {
+		switch op.kind {
+		case diffEqual:
+			fmt.Fprintf(&b, " %s\n", op.line)
+		case diffDelete:
+			fmt.Fprintf(&b, "-%s\n", op.line)
+		case diffInsert:
+			fmt.Fprintf(&b, "+%s\n", op.line)
 		}
+	}
+	return b.String()
+}
 
-		for _, line := range inputBlock {
-			trimmedLine := strings.TrimSpace(line)
+// diffLines computes a minimal line-based edit script between a and b using
+// the standard LCS dynamic program. Test files are small enough that the
+// O(len(a)*len(b)) cost is negligible.
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
 			switch {
-			case strings.HasPrefix(trimmedLine, "expected_fail_message"):
-				msg := strings.TrimPrefix(trimmedLine, "expected_fail_message ")
-				for j, s := range annotations {
-					if strings.Contains(s, "expect fail") {
-						annotations[j] = indent + fmt.Sprintf("expect fail msg:%s", msg)
-					}
-				}
-			case strings.HasPrefix(trimmedLine, "expected_fail_regexp"):
-				regex := strings.TrimPrefix(trimmedLine, "expected_fail_regexp ")
-				for j, s := range annotations {
-					if strings.Contains(s, "expect fail") {
-						annotations[j] = indent + fmt.Sprintf("expect fail regex:%s", regex)
-					}
-				}
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
 			default:
-				outputBlock = append(outputBlock, line)
+				lcs[i][j] = lcs[i][j+1]
 			}
 		}
-
-		result = append(result, annotations...)
-		result = append(result, outputBlock...)
 	}
 
-	return result, nil
+	var ops []diffOp
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{diffEqual, a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{diffDelete, a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{diffInsert, b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, diffOp{diffDelete, a[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, diffOp{diffInsert, b[j]})
+	}
+	return ops
 }
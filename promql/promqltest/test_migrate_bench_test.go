@@ -0,0 +1,60 @@
+package promqltest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// BenchmarkPreviewMigrateTestDataSequential and
+// BenchmarkPreviewMigrateTestDataParallel migrate the same fixture tree
+// under MigrateParallelism=1 and MigrateParallelism=runtime.NumCPU()
+// respectively, so that `go test -bench Preview -benchtime=5x` (or
+// benchstat across both) demonstrates the speedup from the worker pool.
+//
+// The fixture is synthesized rather than pointed at the production
+// promql/promqltest/testdata constant: `go test` runs with the package
+// directory as its working directory, so a path relative to the repository
+// root (as MigrateTestData's defaultTestDataDir is) would not resolve here.
+func BenchmarkPreviewMigrateTestDataSequential(b *testing.B) {
+	benchmarkPreviewMigrateTestData(b, 1)
+}
+
+func BenchmarkPreviewMigrateTestDataParallel(b *testing.B) {
+	benchmarkPreviewMigrateTestData(b, runtime.NumCPU())
+}
+
+func benchmarkPreviewMigrateTestData(b *testing.B, parallelism int) {
+	old := MigrateParallelism
+	MigrateParallelism = parallelism
+	b.Cleanup(func() { MigrateParallelism = old })
+
+	dir := writeMigrateFixture(b, 50)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := migrateTestData(dir, "strict", EmitText, false); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// writeMigrateFixture writes n .test files using deprecated eval commands
+// into a temporary directory, so migrateTestData has real rewriting work to
+// do on every iteration.
+func writeMigrateFixture(b *testing.B, n int) string {
+	b.Helper()
+	dir := b.TempDir()
+	for i := 0; i < n; i++ {
+		content := fmt.Sprintf(
+			"load 5m\n  metric{a=\"b\"} 1 2 3\n\neval_fail instant at 1m metric{a=\"b\"}\n  metric{a=\"b\"} 3\n",
+		)
+		path := filepath.Join(dir, fmt.Sprintf("fixture_%d.test", i))
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			b.Fatalf("writing fixture %s: %v", path, err)
+		}
+	}
+	return dir
+}
@@ -0,0 +1,197 @@
+package promqltest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/prometheus/promql/promqltest/promqltestfmt"
+)
+
+func TestParseMigrateMode(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    MigrateMode
+		wantErr bool
+	}{
+		{in: "strict", want: MigrateStrict},
+		{in: "basic", want: MigrateBasic},
+		{in: "tolerant", want: MigrateTolerant},
+		{in: "bogus", wantErr: true},
+	}
+	for _, tc := range cases {
+		got, err := ParseMigrateMode(tc.in)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("ParseMigrateMode(%q): expected an error", tc.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseMigrateMode(%q): %v", tc.in, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("ParseMigrateMode(%q) = %v, want %v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestParseMigrateEmit(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    MigrateEmit
+		wantErr bool
+	}{
+		{in: "", want: EmitText},
+		{in: "text", want: EmitText},
+		{in: "yaml", want: EmitYAML},
+		{in: "both", want: EmitBoth},
+		{in: "bogus", wantErr: true},
+	}
+	for _, tc := range cases {
+		got, err := ParseMigrateEmit(tc.in)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("ParseMigrateEmit(%q): expected an error", tc.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseMigrateEmit(%q): %v", tc.in, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("ParseMigrateEmit(%q) = %v, want %v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	old := []string{"a", "b", "c"}
+	new := []string{"a", "x", "c"}
+
+	diff := unifiedDiff("path/to/file", old, new)
+	want := "--- a/path/to/file\n" +
+		"+++ b/path/to/file\n" +
+		" a\n" +
+		"-b\n" +
+		"+x\n" +
+		" c\n"
+	if diff != want {
+		t.Fatalf("unifiedDiff mismatch:\ngot:\n%s\nwant:\n%s", diff, want)
+	}
+}
+
+func TestUnifiedDiffNoChange(t *testing.T) {
+	lines := []string{"a", "b"}
+	if diff := unifiedDiff("path", lines, lines); diff != "" {
+		t.Fatalf("unifiedDiff for identical inputs = %q, want empty", diff)
+	}
+}
+
+func TestTransformAnnotationsMigratesDeprecatedCommand(t *testing.T) {
+	f, err := promqltestfmt.Parse([]byte("eval_fail instant at 1m foo\n  foo 1\n"))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	stats := transformAnnotations(f, expectationKindsByMode[MigrateStrict])
+	if stats.touched != 1 || stats.skipped != 0 || stats.failed != 0 {
+		t.Fatalf("stats = %+v, want touched=1", stats)
+	}
+
+	eb := f.Blocks[0].(*promqltestfmt.EvalBlock)
+	if eb.Command != "eval" {
+		t.Fatalf("Command = %q, want eval", eb.Command)
+	}
+	if len(eb.Expectations) == 0 {
+		t.Fatalf("expected expectations to be added, got none")
+	}
+}
+
+func TestTransformAnnotationsSkipsAlreadyMigratedBlock(t *testing.T) {
+	f, err := promqltestfmt.Parse([]byte("eval instant at 1m foo\n  expect no_warn\n  foo 1\n"))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	stats := transformAnnotations(f, expectationKindsByMode[MigrateStrict])
+	if stats.touched != 0 || stats.skipped != 1 || stats.failed != 0 {
+		t.Fatalf("stats = %+v, want skipped=1", stats)
+	}
+}
+
+func TestTransformAnnotationsFailsAmbiguousBlock(t *testing.T) {
+	f, err := promqltestfmt.Parse([]byte(
+		"eval_fail instant at 1m foo\n" +
+			"  expected_fail_message boom\n" +
+			"  expected_fail_regexp boo.*\n" +
+			"  foo 1\n"))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	stats := transformAnnotations(f, expectationKindsByMode[MigrateStrict])
+	if stats.touched != 0 || stats.skipped != 0 || stats.failed != 1 {
+		t.Fatalf("stats = %+v, want failed=1", stats)
+	}
+
+	eb := f.Blocks[0].(*promqltestfmt.EvalBlock)
+	if eb.Command != "eval_fail" {
+		t.Fatalf("Command = %q, want the deprecated command left untouched", eb.Command)
+	}
+}
+
+// An EmitYAML-only preview never rewrites the .test file, so it must not
+// report a text Diff that a subsequent real -emit=yaml run could never
+// apply.
+func TestMigrateFileEmitYAMLOnlyHasNoTextDiff(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.test")
+	content := "eval_fail instant at 1m foo\n  foo 1\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	previews, err := migrateTestData(dir, "strict", EmitYAML, false)
+	if err != nil {
+		t.Fatalf("migrateTestData: %v", err)
+	}
+	if len(previews) != 1 {
+		t.Fatalf("previews = %v, want exactly one", previews)
+	}
+
+	p := previews[0]
+	if p.Diff != "" {
+		t.Errorf("Diff = %q, want empty: -emit=yaml never rewrites the .test file", p.Diff)
+	}
+	if p.YAMLDiff == "" {
+		t.Errorf("YAMLDiff is empty, want a diff for the new .test.yaml sibling")
+	}
+	if p.BlocksTouched != 1 {
+		t.Errorf("BlocksTouched = %d, want 1", p.BlocksTouched)
+	}
+}
+
+// An EmitText preview (the default) still reports the text Diff, unaffected
+// by the EmitYAML-only gating above.
+func TestMigrateFileEmitTextHasTextDiff(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.test")
+	content := "eval_fail instant at 1m foo\n  foo 1\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	previews, err := migrateTestData(dir, "strict", EmitText, false)
+	if err != nil {
+		t.Fatalf("migrateTestData: %v", err)
+	}
+	if len(previews) != 1 {
+		t.Fatalf("previews = %v, want exactly one", previews)
+	}
+	if previews[0].Diff == "" {
+		t.Errorf("Diff is empty, want a diff for the rewritten .test file")
+	}
+}